@@ -6,15 +6,19 @@ import (
 	"fmt"
 	"image"
 	"image/color/palette"
+	stdgif "image/gif"
 	"image/jpeg"
 	"image/png"
 	_ "image/png"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/esimov/colorquant"
+	"github.com/esimov/colorquant/gif"
+	"github.com/esimov/colorquant/imageio"
 )
 
 type file struct {
@@ -27,6 +31,11 @@ var (
 	ditherer    string
 	imageType   string
 	noDither    bool
+	ordered     bool
+	quantizer   string
+	colorSpace  string
+	parallelism int
+	respectEXIF bool
 	compression int
 	numColors   int
 	commands    flag.FlagSet
@@ -40,24 +49,51 @@ Usage of commands:
     	Dithering method. (default "FloydSteinberg")
   -no-dither
     	Use image quantizer without dithering.
+  -ordered
+    	Use ordered (Bayer / clustered-dot) dithering. The -ditherer value
+    	selects the threshold map: Bayer2, Bayer4, Bayer8 or ClusteredDot4x4.
   -output string
     	Output directory. (default "output")
   -palette int
     	The number of palette colors. (default 256)
+  -quantizer string
+    	Palette generator: websafe, median or kmeans. (default "websafe")
+  -color-space string
+    	Space error diffusion runs in: srgb, linear or lab. (default "srgb")
+  -parallelism int
+    	Diffuse error with this many concurrent row workers.
+    	0 keeps the serial, serpentine scan. (default 0)
+  -respect-exif
+    	Rotate/flip the input according to its EXIF orientation tag. (default true)
   -type string
-    	Image type. Possible options .jpg, .png (default "jpg")
+    	Image type. Possible options .jpg, .png, .gif (default "jpg")
+
+For -type gif, the input path is a directory of frame images (read in
+sorted filename order) rather than a single file.
 `
 
+var quantizers = map[string]colorquant.Quantizer{
+	"websafe": colorquant.FixedQuantizer{Palette: palette.WebSafe},
+	"median":  colorquant.MedianCutQuantizer{},
+	"kmeans":  colorquant.KMeansQuantizer{},
+}
+
+var colorSpaces = map[string]colorquant.ColorSpace{
+	"srgb":   colorquant.SRGB,
+	"linear": colorquant.LinearRGB,
+	"lab":    colorquant.CIELab,
+}
+
 var dither map[string]colorquant.Dither = map[string]colorquant.Dither{
 	"FloydSteinberg": colorquant.Dither{
-		[][]float32{
+		Matrix: [][]float32{
 			[]float32{0.0, 0.0, 0.0, 7.0 / 48.0, 5.0 / 48.0},
 			[]float32{3.0 / 48.0, 5.0 / 48.0, 7.0 / 48.0, 5.0 / 48.0, 3.0 / 48.0},
 			[]float32{1.0 / 48.0, 3.0 / 48.0, 5.0 / 48.0, 3.0 / 48.0, 1.0 / 48.0},
 		},
 	},
 	"Burkes": colorquant.Dither{
-		[][]float32{
+		Matrix: [][]float32{
 			[]float32{0.0, 0.0, 0.0, 8.0 / 32.0, 4.0 / 32.0},
 			[]float32{2.0 / 32.0, 4.0 / 32.0, 8.0 / 32.0, 4.0 / 32.0, 2.0 / 32.0},
 			[]float32{0.0, 0.0, 0.0, 0.0, 0.0},
@@ -65,35 +101,35 @@ var dither map[string]colorquant.Dither = map[string]colorquant.Dither{
 		},
 	},
 	"Stucki": colorquant.Dither{
-		[][]float32{
+		Matrix: [][]float32{
 			[]float32{0.0, 0.0, 0.0, 8.0 / 42.0, 4.0 / 42.0},
 			[]float32{2.0 / 42.0, 4.0 / 42.0, 8.0 / 42.0, 4.0 / 42.0, 2.0 / 42.0},
 			[]float32{1.0 / 42.0, 2.0 / 42.0, 4.0 / 42.0, 2.0 / 42.0, 1.0 / 42.0},
 		},
 	},
 	"Atkinson": colorquant.Dither{
-		[][]float32{
+		Matrix: [][]float32{
 			[]float32{0.0, 0.0, 1.0 / 8.0, 1.0 / 8.0},
 			[]float32{1.0 / 8.0, 1.0 / 8.0, 1.0 / 8.0, 0.0},
 			[]float32{0.0, 1.0 / 8.0, 0.0, 0.0},
 		},
 	},
 	"Sierra-3": colorquant.Dither{
-		[][]float32{
+		Matrix: [][]float32{
 			[]float32{0.0, 0.0, 0.0, 5.0 / 32.0, 3.0 / 32.0},
 			[]float32{2.0 / 32.0, 4.0 / 32.0, 5.0 / 32.0, 4.0 / 32.0, 2.0 / 32.0},
 			[]float32{0.0, 2.0 / 32.0, 3.0 / 32.0, 2.0 / 32.0, 0.0},
 		},
 	},
 	"Sierra-2": colorquant.Dither{
-		[][]float32{
+		Matrix: [][]float32{
 			[]float32{0.0, 0.0, 0.0, 4.0 / 16.0, 3.0 / 16.0},
 			[]float32{1.0 / 16.0, 2.0 / 16.0, 3.0 / 16.0, 2.0 / 16.0, 1.0 / 16.0},
 			[]float32{0.0, 0.0, 0.0, 0.0, 0.0},
 		},
 	},
 	"Sierra-Lite": colorquant.Dither{
-		[][]float32{
+		Matrix: [][]float32{
 			[]float32{0.0, 0.0, 2.0 / 4.0},
 			[]float32{1.0 / 4.0, 1.0 / 4.0, 0.0},
 			[]float32{0.0, 0.0, 0.0},
@@ -101,15 +137,18 @@ var dither map[string]colorquant.Dither = map[string]colorquant.Dither{
 	},
 }
 
-// Open image
-func (file *file) Open() (image.Image, error) {
-	f, err := os.Open(file.name)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
+var orderedDither = map[string]colorquant.Ordered{
+	"Bayer2":          colorquant.Bayer2,
+	"Bayer4":          colorquant.Bayer4,
+	"Bayer8":          colorquant.Bayer8,
+	"ClusteredDot4x4": colorquant.ClusteredDot4x4,
+}
 
-	img, _, err := image.Decode(f)
+// Open decodes the image, applying the rotate/flip implied by its EXIF
+// orientation tag (if any and if -respect-exif is set) so portrait JPEGs
+// don't come out sideways.
+func (file *file) Open() (image.Image, error) {
+	img, _, err := imageio.Open(file.name, respectEXIF)
 	return img, err
 }
 
@@ -118,17 +157,34 @@ func (file *file) Quantify(src image.Image, output string) (image.Image, error)
 	var err error
 	var quant image.Image
 
-	dst := image.NewPaletted(image.Rect(0, 0, src.Bounds().Dx(), src.Bounds().Dy()), palette.WebSafe)
-	if noDither {
+	pal := palette.WebSafe
+	if q, ok := quantizers[quantizer]; ok {
+		pal = q.Quantize(src, numColors)
+	}
+
+	dst := image.NewPaletted(image.Rect(0, 0, src.Bounds().Dx(), src.Bounds().Dy()), pal)
+	switch {
+	case noDither:
 		quant = colorquant.NoDither.Quantize(src, dst, numColors, false, true)
-	} else {
+	case ordered:
+		om, ok := orderedDither[ditherer]
+		if !ok {
+			log.Fatal("\nInvalid ordered dithering method!")
+			return nil, err
+		}
+		quant = om.Quantize(src, dst, numColors)
+	default:
 		if _, ok := dither[ditherer]; !ok {
 			log.Fatal("\nInvalid dithering method!")
 			return nil, err
 		}
 
-		ditherer := dither[ditherer]
-		quant = ditherer.Quantize(src, dst, numColors, true, true)
+		d := dither[ditherer]
+		d.Space = colorSpaces[colorSpace]
+		d.Parallelism = parallelism
+		// The parallel wavefront scan requires a one-directional scan, so
+		// serpentine is disabled whenever parallelism is requested.
+		quant = d.Quantize(src, dst, numColors, true, parallelism == 0)
 	}
 
 	fq, err := os.Create(output)
@@ -152,14 +208,65 @@ func (file *file) Quantify(src image.Image, output string) (image.Image, error)
 	return quant, nil
 }
 
+// QuantifyGIF reads every frame image in dir (in sorted filename order),
+// quantizes them against one shared palette and writes the resulting
+// animation to output.
+func (file *file) QuantifyGIF(dir, output string) error {
+	entries, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(entries)
+
+	var frames []image.Image
+	for _, path := range entries {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		img, _, err := imageio.Decode(f, respectEXIF)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		frames = append(frames, img)
+	}
+
+	d := dither[ditherer]
+	d.Space = colorSpaces[colorSpace]
+	anim, err := gif.Build(frames, gif.Options{
+		NumColors: numColors,
+		Quantizer: quantizers[quantizer],
+		Dither:    d,
+		Delay:     10,
+		Stable:    true,
+	})
+	if err != nil {
+		return err
+	}
+
+	fq, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer fq.Close()
+
+	return stdgif.EncodeAll(fq, anim)
+}
+
 func main() {
 	commands = *flag.NewFlagSet("commands", flag.ExitOnError)
 	commands.StringVar(&output, "output", "output", "Output directory.")
 	commands.StringVar(&ditherer, "ditherer", "FloydSteinberg", "Dithering method.")
-	commands.StringVar(&imageType, "type", "jpg", "Image type. Possible options .jpg, .png")
+	commands.StringVar(&imageType, "type", "jpg", "Image type. Possible options .jpg, .png, .gif")
 	commands.BoolVar(&noDither, "no-dither", false, "Use image quantizer without dithering.")
+	commands.BoolVar(&ordered, "ordered", false, "Use ordered (Bayer / clustered-dot) dithering.")
 	commands.IntVar(&compression, "compression", 100, "JPEG compression.")
 	commands.IntVar(&numColors, "palette", 256, "The number of palette colors.")
+	commands.StringVar(&quantizer, "quantizer", "websafe", "Palette generator: websafe, median or kmeans.")
+	commands.StringVar(&colorSpace, "color-space", "srgb", "Space error diffusion runs in: srgb, linear or lab.")
+	commands.IntVar(&parallelism, "parallelism", 0, "Diffuse error with this many concurrent row workers. 0 keeps the serial, serpentine scan.")
+	commands.BoolVar(&respectEXIF, "respect-exif", true, "Rotate/flip the input according to its EXIF orientation tag.")
 
 	if len(os.Args) <= 1 || (os.Args[1] == "--help" || os.Args[1] == "-h") {
 		fmt.Println(errors.New(helper))
@@ -173,6 +280,7 @@ func main() {
 	done := make(chan struct{})
 	input := &file{name: string(os.Args[1])}
 	img, _ := input.Open()
+	absInputName, _ := filepath.Abs(input.name)
 
 	if commands.Parsed() {
 		if numColors <= 1 {
@@ -210,6 +318,10 @@ func main() {
 				} else {
 					input.Quantify(img, ditherer+".png")
 				}
+			case "gif":
+				if err := input.QuantifyGIF(absInputName, "output.gif"); err != nil {
+					log.Fatal(err)
+				}
 			}
 			done <- struct{}{}
 		}(input, done)
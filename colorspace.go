@@ -0,0 +1,113 @@
+package colorquant
+
+import "math"
+
+// ColorSpace selects where a Dither computes and diffuses quantization
+// error. Diffusing in sRGB (the default) is cheap but distorts gradients,
+// since sRGB values are not perceptually or physically linear.
+type ColorSpace int
+
+const (
+	// SRGB diffuses error directly on the encoded sRGB channel values, as
+	// Dither has always done.
+	SRGB ColorSpace = iota
+	// LinearRGB diffuses error in linear light, undoing the sRGB transfer
+	// function before quantization and reapplying it before the pixel is
+	// written out.
+	LinearRGB
+	// CIELab diffuses error in the perceptually uniform CIE L*a*b* space.
+	CIELab
+)
+
+// srgbToLinear converts a single sRGB channel value in [0, 1] to linear
+// light using the standard sRGB transfer function.
+func srgbToLinear(v float32) float32 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return float32(math.Pow(float64((v+0.055)/1.055), 2.4))
+}
+
+// linearToSRGB is the inverse of srgbToLinear.
+func linearToSRGB(v float32) float32 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return float32(1.055*math.Pow(float64(v), 1/2.4) - 0.055)
+}
+
+// d65White is the CIE 1931 2-degree D65 white point, used to normalize the
+// XYZ tristimulus values before converting to Lab.
+var d65White = [3]float32{0.95047, 1.0, 1.08883}
+
+// rgbToXYZ converts linear-light RGB (each channel in [0, 1]) to CIE XYZ
+// under the D65 illuminant.
+func rgbToXYZ(r, g, b float32) (x, y, z float32) {
+	x = r*0.4124564 + g*0.3575761 + b*0.1804375
+	y = r*0.2126729 + g*0.7151522 + b*0.0721750
+	z = r*0.0193339 + g*0.1191920 + b*0.9503041
+	return
+}
+
+// xyzToRGB is the inverse of rgbToXYZ, returning linear-light RGB.
+func xyzToRGB(x, y, z float32) (r, g, b float32) {
+	r = x*3.2404542 + y*-1.5371385 + z*-0.4985314
+	g = x*-0.9692660 + y*1.8760108 + z*0.0415560
+	b = x*0.0556434 + y*-0.2040259 + z*1.0572252
+	return
+}
+
+// labF is the piecewise cube-root response curve used by the XYZ->Lab
+// conversion.
+func labF(t float32) float32 {
+	const delta = 6.0 / 29.0
+	if t > delta*delta*delta {
+		return float32(math.Cbrt(float64(t)))
+	}
+	return t/(3*delta*delta) + 4.0/29.0
+}
+
+// labFInv is the inverse of labF, used by the Lab->XYZ conversion.
+func labFInv(t float32) float32 {
+	const delta = 6.0 / 29.0
+	if t > delta {
+		return t * t * t
+	}
+	return 3 * delta * delta * (t - 4.0/29.0)
+}
+
+// srgbToLab converts a pixel's sRGB channels (each in [0, 255]) to CIE
+// L*a*b*, where L is in [0, 100] and a*/b* are roughly in [-128, 127].
+func srgbToLab(r, g, b float32) (l, a, bb float32) {
+	lr := srgbToLinear(r / 255)
+	lg := srgbToLinear(g / 255)
+	lb := srgbToLinear(b / 255)
+	x, y, z := rgbToXYZ(lr, lg, lb)
+
+	fx := labF(x / d65White[0])
+	fy := labF(y / d65White[1])
+	fz := labF(z / d65White[2])
+
+	l = 116*fy - 16
+	a = 500 * (fx - fy)
+	bb = 200 * (fy - fz)
+	return
+}
+
+// labToSRGB is the inverse of srgbToLab, returning sRGB channels in
+// [0, 255].
+func labToSRGB(l, a, b float32) (r, g, bl float32) {
+	fy := (l + 16) / 116
+	fx := fy + a/500
+	fz := fy - b/200
+
+	x := labFInv(fx) * d65White[0]
+	y := labFInv(fy) * d65White[1]
+	z := labFInv(fz) * d65White[2]
+
+	lr, lg, lb := xyzToRGB(x, y, z)
+	r = linearToSRGB(lr) * 255
+	g = linearToSRGB(lg) * 255
+	bl = linearToSRGB(lb) * 255
+	return
+}
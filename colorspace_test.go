@@ -0,0 +1,62 @@
+package colorquant
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSRGBLinearRoundTrip(t *testing.T) {
+	for v := 0; v <= 255; v++ {
+		in := float32(v) / 255
+		got := linearToSRGB(srgbToLinear(in))
+		if diff := math.Abs(float64(got - in)); diff > 1e-4 {
+			t.Fatalf("srgbToLinear/linearToSRGB round trip for %v: got %v, diff %v", in, got, diff)
+		}
+	}
+}
+
+func TestSRGBLabRoundTrip(t *testing.T) {
+	samples := [][3]float32{
+		{0, 0, 0},
+		{255, 255, 255},
+		{255, 0, 0},
+		{0, 255, 0},
+		{0, 0, 255},
+		{128, 64, 200},
+		{17, 233, 99},
+	}
+
+	for _, c := range samples {
+		l, a, b := srgbToLab(c[0], c[1], c[2])
+		r, g, bl := labToSRGB(l, a, b)
+
+		// A full 8-bit channel round trip through sRGB<->linear<->XYZ<->Lab
+		// accumulates float32 rounding; allow a little over half a channel
+		// step.
+		const tol = 0.6
+		if diff := math.Abs(float64(r - c[0])); diff > tol {
+			t.Errorf("R round trip for %v: got %v, diff %v", c, r, diff)
+		}
+		if diff := math.Abs(float64(g - c[1])); diff > tol {
+			t.Errorf("G round trip for %v: got %v, diff %v", c, g, diff)
+		}
+		if diff := math.Abs(float64(bl - c[2])); diff > tol {
+			t.Errorf("B round trip for %v: got %v, diff %v", c, bl, diff)
+		}
+	}
+}
+
+func TestLabWhiteAndBlackPoints(t *testing.T) {
+	l, a, b := srgbToLab(0, 0, 0)
+	if math.Abs(float64(l)) > 1e-2 || math.Abs(float64(a)) > 1e-2 || math.Abs(float64(b)) > 1e-2 {
+		t.Errorf("black should map to L*a*b* (0,0,0), got (%v,%v,%v)", l, a, b)
+	}
+
+	l, a, b = srgbToLab(255, 255, 255)
+	if diff := math.Abs(float64(l - 100)); diff > 0.5 {
+		t.Errorf("white should map to L near 100, got %v", l)
+	}
+	if math.Abs(float64(a)) > 0.5 || math.Abs(float64(b)) > 0.5 {
+		t.Errorf("white should map to a*b* near (0,0), got (%v,%v)", a, b)
+	}
+}
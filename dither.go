@@ -0,0 +1,179 @@
+// Package colorquant implements image color quantization and dithering.
+package colorquant
+
+import (
+	"image"
+	"image/color"
+)
+
+// Dither holds the error diffusion matrix used to spread the quantization
+// error of a pixel onto its yet-unprocessed neighbours. Matrix is indexed
+// [row][col], row 0 being the current scanline and subsequent rows the
+// scanlines below it. Within a row, the weight assigned to the current
+// pixel lives at column (len(row)-1)/2; columns to either side carry the
+// share of the error propagated to the neighbouring pixel at that offset.
+type Dither struct {
+	Matrix [][]float32
+
+	// Linearize, when true, undoes the sRGB transfer function before
+	// diffusing error and reapplies it before writing the pixel out. It is
+	// a shorthand for Space == LinearRGB and is ignored when Space is set
+	// to CIELab.
+	Linearize bool
+	// Space selects the color space error is computed and diffused in.
+	// The zero value, SRGB, reproduces Dither's original behavior.
+	Space ColorSpace
+
+	// Parallelism, when greater than zero, diffuses error using this many
+	// concurrent workers on a wavefront schedule (see quantizeParallel)
+	// instead of the single-threaded scan. It only takes effect when
+	// serpentine scanning is off, since the wavefront schedule assumes
+	// every row scans in the same direction; Quantize falls back to the
+	// serial scan for serpentine. The zero value keeps the original
+	// single-threaded scan.
+	Parallelism int
+}
+
+// NoDither quantizes an image without distributing any error, i.e. it maps
+// every pixel directly to the nearest palette color.
+var NoDither = Dither{}
+
+// Quantize reduces src to at most nq colors from dst's palette, writing the
+// result into dst. When dither is true the quantization error of each pixel
+// is diffused to its neighbours using d.Matrix; when serpentine is true the
+// scan direction alternates between left-to-right and right-to-left on
+// successive rows, which avoids the directional streaking a one-way scan
+// produces.
+func (d Dither) Quantize(src, dst image.Image, nq int, dither, serpentine bool) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	paletted, ok := dst.(*image.Paletted)
+	if !ok {
+		paletted = image.NewPaletted(bounds, color.Palette(nil))
+	}
+	pal := paletted.Palette
+	if len(pal) > nq {
+		pal = pal[:nq]
+	}
+
+	if dither && !serpentine && d.Parallelism > 0 {
+		d.quantizeParallel(src, paletted, pal, width, height)
+		return paletted
+	}
+
+	var errR, errG, errB [][]float32
+	if dither {
+		errR = make([][]float32, height)
+		errG = make([][]float32, height)
+		errB = make([][]float32, height)
+		for y := 0; y < height; y++ {
+			errR[y] = make([]float32, width)
+			errG[y] = make([]float32, width)
+			errB[y] = make([]float32, width)
+		}
+	}
+
+	for y := 0; y < height; y++ {
+		leftToRight := !serpentine || y%2 == 0
+		for i := 0; i < width; i++ {
+			x := i
+			if !leftToRight {
+				x = width - 1 - i
+			}
+
+			r, g, b, a := src.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			wr, wg, wb := d.ToWorkingSpace(float32(r>>8), float32(g>>8), float32(b>>8))
+			if dither {
+				wr += errR[y][x]
+				wg += errG[y][x]
+				wb += errB[y][x]
+			}
+
+			sr, sg, sb := d.FromWorkingSpace(wr, wg, wb)
+			sr, sg, sb = clamp8(sr), clamp8(sg), clamp8(sb)
+
+			orig := color.RGBA{uint8(sr), uint8(sg), uint8(sb), uint8(a >> 8)}
+			idx := pal.Index(orig)
+			quant := pal[idx]
+			paletted.SetColorIndex(bounds.Min.X+x, bounds.Min.Y+y, uint8(idx))
+
+			if !dither {
+				continue
+			}
+
+			qr, qg, qb, _ := quant.RGBA()
+			qwr, qwg, qwb := d.ToWorkingSpace(float32(qr>>8), float32(qg>>8), float32(qb>>8))
+			errCr := wr - qwr
+			errCg := wg - qwg
+			errCb := wb - qwb
+
+			for row, weights := range d.Matrix {
+				ny := y + row
+				if ny >= height {
+					continue
+				}
+				center := (len(weights) - 1) / 2
+				for col, w := range weights {
+					if row == 0 && col == center {
+						continue
+					}
+					offset := col - center
+					if !leftToRight {
+						offset = -offset
+					}
+					nx := x + offset
+					if nx < 0 || nx >= width || w == 0 {
+						continue
+					}
+					errR[ny][nx] += errCr * w
+					errG[ny][nx] += errCg * w
+					errB[ny][nx] += errCb * w
+				}
+			}
+		}
+	}
+
+	return paletted
+}
+
+// ToWorkingSpace converts an sRGB pixel (each channel in [0, 255]) into the
+// space d.Space (or linear RGB, if only Linearize is set) so that error can
+// be computed and diffused there instead of in raw sRGB. It is exported so
+// packages that diffuse error themselves, such as gif, can honor Space and
+// Linearize the same way Quantize does.
+func (d Dither) ToWorkingSpace(r, g, b float32) (float32, float32, float32) {
+	switch {
+	case d.Space == CIELab:
+		return srgbToLab(r, g, b)
+	case d.Space == LinearRGB || d.Linearize:
+		return srgbToLinear(r/255) * 255, srgbToLinear(g/255) * 255, srgbToLinear(b/255) * 255
+	default:
+		return r, g, b
+	}
+}
+
+// FromWorkingSpace is the inverse of ToWorkingSpace, converting back to
+// sRGB (each channel in [0, 255]) for the final palette lookup and output.
+func (d Dither) FromWorkingSpace(r, g, b float32) (float32, float32, float32) {
+	switch {
+	case d.Space == CIELab:
+		return labToSRGB(r, g, b)
+	case d.Space == LinearRGB || d.Linearize:
+		return linearToSRGB(r/255) * 255, linearToSRGB(g/255) * 255, linearToSRGB(b/255) * 255
+	default:
+		return r, g, b
+	}
+}
+
+// clamp8 restricts v to the [0, 255] range covered by a single color channel.
+func clamp8(v float32) float32 {
+	switch {
+	case v < 0:
+		return 0
+	case v > 255:
+		return 255
+	default:
+		return v
+	}
+}
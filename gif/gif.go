@@ -0,0 +1,220 @@
+// Package gif builds animated GIFs whose frames share a single quantized
+// palette, computed once across the whole animation rather than per frame.
+// Reusing one palette avoids the color flicker a per-frame quantizer
+// produces on flat or slowly-changing regions.
+package gif
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	stdgif "image/gif"
+	"math/rand"
+
+	"github.com/esimov/colorquant"
+)
+
+// Options configures Build.
+type Options struct {
+	// NumColors is the size of the shared palette.
+	NumColors int
+	// Quantizer builds the shared palette from the sampled pixels. Defaults
+	// to colorquant.MedianCutQuantizer{}.
+	Quantizer colorquant.Quantizer
+	// Dither is the error-diffusion matrix applied to every frame. The zero
+	// value, colorquant.NoDither, disables dithering.
+	Dither colorquant.Dither
+	// SampleCap bounds how many pixels, across all frames combined, are fed
+	// into Quantizer. Zero means no cap.
+	SampleCap int
+	// Delay is the per-frame delay in hundredths of a second, used for
+	// every frame of the output GIF.
+	Delay int
+	// Stable carries each frame's diffused error into the next frame
+	// instead of restarting at zero, which reduces shimmer in regions that
+	// barely change between frames. Error is only carried between frames
+	// of the same width.
+	Stable bool
+}
+
+// Build quantizes frames against a single palette sampled across all of
+// them, dithers each frame against that shared palette, and returns a
+// *stdgif.GIF ready to be encoded with image/gif.EncodeAll.
+func Build(frames []image.Image, opts Options) (*stdgif.GIF, error) {
+	if opts.NumColors <= 0 {
+		return nil, fmt.Errorf("gif: Options.NumColors must be > 0, got %d", opts.NumColors)
+	}
+
+	quantizer := opts.Quantizer
+	if quantizer == nil {
+		quantizer = colorquant.MedianCutQuantizer{}
+	}
+
+	samples := sampleFrames(frames, opts.SampleCap)
+	pal := quantizer.Quantize(&sampleImage{samples}, opts.NumColors)
+
+	out := &stdgif.GIF{}
+	var carry *errorBuffer
+	for _, frame := range frames {
+		paletted, next := diffuseFrame(frame, pal, opts.Dither, carry)
+		if opts.Stable {
+			carry = next
+		}
+
+		out.Image = append(out.Image, paletted)
+		out.Delay = append(out.Delay, opts.Delay)
+		out.Disposal = append(out.Disposal, stdgif.DisposalNone)
+	}
+	return out, nil
+}
+
+// sampleFrames flattens every frame's pixels into a single slice, capping
+// the total at cap pixels using reservoir sampling (Algorithm R) so that
+// every pixel across every frame has an equal chance of being kept
+// regardless of how many frames or how large they are.
+func sampleFrames(frames []image.Image, cap int) []color.RGBA {
+	var samples []color.RGBA
+	seen := 0
+
+	visit := func(c color.RGBA) {
+		seen++
+		if cap <= 0 || len(samples) < cap {
+			samples = append(samples, c)
+			return
+		}
+		if j := rand.Intn(seen); j < cap {
+			samples[j] = c
+		}
+	}
+
+	for _, frame := range frames {
+		b := frame.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				r, g, bl, a := frame.At(x, y).RGBA()
+				visit(color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(bl >> 8), uint8(a >> 8)})
+			}
+		}
+	}
+	return samples
+}
+
+// sampleImage adapts a flat slice of sampled pixels into an image.Image so
+// it can be fed into the colorquant.Quantizer interface, which otherwise
+// only ever sees one frame at a time.
+type sampleImage struct {
+	pixels []color.RGBA
+}
+
+func (s *sampleImage) ColorModel() color.Model { return color.RGBAModel }
+func (s *sampleImage) Bounds() image.Rectangle { return image.Rect(0, 0, len(s.pixels), 1) }
+func (s *sampleImage) At(x, y int) color.Color {
+	if x < 0 || x >= len(s.pixels) {
+		return color.RGBA{}
+	}
+	return s.pixels[x]
+}
+
+// errorBuffer holds the error that diffused past the bottom edge of a
+// frame and would otherwise be discarded. Seeding the next frame's top
+// rows with it is what makes Options.Stable avoid shimmer in regions that
+// barely change between frames.
+type errorBuffer struct {
+	width   int
+	r, g, b [][]float32
+}
+
+// diffuseFrame quantizes frame against pal, diffusing error using dither's
+// matrix in dither's color space (see colorquant.Dither.Space). If carry is
+// non-nil and was produced by a same-width frame, its rows seed the top of
+// the error accumulators instead of starting from zero. It returns the
+// quantized frame and the error that diffused past its bottom edge, for the
+// caller to pass into the next frame.
+func diffuseFrame(frame image.Image, pal color.Palette, dither colorquant.Dither, carry *errorBuffer) (*image.Paletted, *errorBuffer) {
+	matrix := dither.Matrix
+	bounds := frame.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	paletted := image.NewPaletted(bounds, pal)
+
+	overflow := len(matrix) - 1
+	if overflow < 0 {
+		overflow = 0
+	}
+
+	errR := make([][]float32, height+overflow)
+	errG := make([][]float32, height+overflow)
+	errB := make([][]float32, height+overflow)
+	for y := range errR {
+		errR[y] = make([]float32, width)
+		errG[y] = make([]float32, width)
+		errB[y] = make([]float32, width)
+	}
+	if carry != nil && carry.width == width {
+		for y := 0; y < overflow && y < len(carry.r); y++ {
+			copy(errR[y], carry.r[y])
+			copy(errG[y], carry.g[y])
+			copy(errB[y], carry.b[y])
+		}
+	}
+
+	doDither := len(matrix) > 0
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, a := frame.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			wr, wg, wb := dither.ToWorkingSpace(float32(r>>8), float32(g>>8), float32(b>>8))
+			wr += errR[y][x]
+			wg += errG[y][x]
+			wb += errB[y][x]
+
+			cr, cg, cb := dither.FromWorkingSpace(wr, wg, wb)
+			cr, cg, cb = clamp8(cr), clamp8(cg), clamp8(cb)
+
+			idx := pal.Index(color.RGBA{uint8(cr), uint8(cg), uint8(cb), uint8(a >> 8)})
+			paletted.SetColorIndex(bounds.Min.X+x, bounds.Min.Y+y, uint8(idx))
+			if !doDither {
+				continue
+			}
+
+			qr, qg, qb, _ := pal[idx].RGBA()
+			qwr, qwg, qwb := dither.ToWorkingSpace(float32(qr>>8), float32(qg>>8), float32(qb>>8))
+			errCr := wr - qwr
+			errCg := wg - qwg
+			errCb := wb - qwb
+
+			for row, weights := range matrix {
+				ny := y + row
+				center := (len(weights) - 1) / 2
+				for col, w := range weights {
+					if row == 0 && col == center {
+						continue
+					}
+					nx := x + col - center
+					if nx < 0 || nx >= width || ny >= len(errR) || w == 0 {
+						continue
+					}
+					errR[ny][nx] += errCr * w
+					errG[ny][nx] += errCg * w
+					errB[ny][nx] += errCb * w
+				}
+			}
+		}
+	}
+
+	var next *errorBuffer
+	if overflow > 0 {
+		next = &errorBuffer{width: width, r: errR[height:], g: errG[height:], b: errB[height:]}
+	}
+	return paletted, next
+}
+
+// clamp8 restricts v to the [0, 255] range covered by a single color channel.
+func clamp8(v float32) float32 {
+	switch {
+	case v < 0:
+		return 0
+	case v > 255:
+		return 255
+	default:
+		return v
+	}
+}
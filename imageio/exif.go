@@ -0,0 +1,133 @@
+// Package imageio wraps image decoding with EXIF orientation handling, so
+// that a JPEG shot in portrait doesn't come out sideways after quantization.
+package imageio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Orientation is the value of the EXIF orientation tag (0x0112). The eight
+// values correspond to the eight ways a decoder can be told to transform a
+// stored image back to upright, as defined by the EXIF/TIFF spec.
+type Orientation int
+
+const (
+	// OrientationNormal is the default: no transform needed.
+	OrientationNormal Orientation = 1
+	// OrientationFlipHorizontal mirrors the image left-to-right.
+	OrientationFlipHorizontal Orientation = 2
+	// OrientationRotate180 rotates the image 180 degrees.
+	OrientationRotate180 Orientation = 3
+	// OrientationFlipVertical mirrors the image top-to-bottom.
+	OrientationFlipVertical Orientation = 4
+	// OrientationTranspose flips the image across its top-left/bottom-right
+	// diagonal.
+	OrientationTranspose Orientation = 5
+	// OrientationRotate90 rotates the image 90 degrees clockwise.
+	OrientationRotate90 Orientation = 6
+	// OrientationTransverse flips the image across its top-right/bottom-left
+	// diagonal.
+	OrientationTransverse Orientation = 7
+	// OrientationRotate270 rotates the image 270 degrees clockwise (90
+	// counter-clockwise).
+	OrientationRotate270 Orientation = 8
+)
+
+var errNoEXIF = errors.New("imageio: no EXIF orientation tag found")
+
+const orientationTag = 0x0112
+
+// ReadOrientation scans a JPEG stream for its EXIF APP1 segment and returns
+// the value of the orientation tag (0x0112). It returns OrientationNormal,
+// errNoEXIF if the file carries no EXIF data or no orientation tag.
+func ReadOrientation(r io.Reader) (Orientation, error) {
+	br := bufio.NewReader(r)
+
+	soi := make([]byte, 2)
+	if _, err := io.ReadFull(br, soi); err != nil {
+		return OrientationNormal, err
+	}
+	if soi[0] != 0xFF || soi[1] != 0xD8 {
+		return OrientationNormal, errNoEXIF
+	}
+
+	for {
+		marker := make([]byte, 2)
+		if _, err := io.ReadFull(br, marker); err != nil {
+			return OrientationNormal, errNoEXIF
+		}
+		if marker[0] != 0xFF {
+			return OrientationNormal, errNoEXIF
+		}
+		// SOS (start of scan) ends the metadata segments; the rest is
+		// compressed image data.
+		if marker[1] == 0xDA {
+			return OrientationNormal, errNoEXIF
+		}
+
+		lenBuf := make([]byte, 2)
+		if _, err := io.ReadFull(br, lenBuf); err != nil {
+			return OrientationNormal, errNoEXIF
+		}
+		segLen := int(binary.BigEndian.Uint16(lenBuf)) - 2
+		if segLen < 0 {
+			return OrientationNormal, errNoEXIF
+		}
+
+		segment := make([]byte, segLen)
+		if _, err := io.ReadFull(br, segment); err != nil {
+			return OrientationNormal, errNoEXIF
+		}
+
+		if marker[1] == 0xE1 && segLen > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return parseOrientation(segment[6:])
+		}
+	}
+}
+
+// parseOrientation reads the orientation tag out of a TIFF-formatted EXIF
+// payload (the part following the "Exif\0\0" header).
+func parseOrientation(tiff []byte) (Orientation, error) {
+	if len(tiff) < 8 {
+		return OrientationNormal, errNoEXIF
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return OrientationNormal, errNoEXIF
+	}
+
+	ifdOffset := order.Uint32(tiff[4:8])
+	if int(ifdOffset)+2 > len(tiff) {
+		return OrientationNormal, errNoEXIF
+	}
+
+	numEntries := int(order.Uint16(tiff[ifdOffset : ifdOffset+2]))
+	const entrySize = 12
+	base := int(ifdOffset) + 2
+
+	for i := 0; i < numEntries; i++ {
+		start := base + i*entrySize
+		if start+entrySize > len(tiff) {
+			break
+		}
+		entry := tiff[start : start+entrySize]
+		tag := order.Uint16(entry[0:2])
+		if tag != orientationTag {
+			continue
+		}
+		// Orientation is a SHORT packed into the first two bytes of the
+		// entry's 4-byte value field.
+		return Orientation(order.Uint16(entry[8:10])), nil
+	}
+
+	return OrientationNormal, errNoEXIF
+}
@@ -0,0 +1,89 @@
+package imageio
+
+import (
+	"image"
+	"io"
+	"os"
+)
+
+// Decode reads an image from r, applying the rotate/flip implied by its
+// EXIF orientation tag (if any) when respectEXIF is true. r must support
+// Seek, since the orientation tag and the image data are both read from it.
+func Decode(r io.ReadSeeker, respectEXIF bool) (image.Image, string, error) {
+	img, format, err := image.Decode(r)
+	if err != nil {
+		return nil, format, err
+	}
+	if !respectEXIF {
+		return img, format, nil
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return img, format, nil
+	}
+	o, err := ReadOrientation(r)
+	if err != nil || o == OrientationNormal {
+		return img, format, nil
+	}
+	return Apply(img, o), format, nil
+}
+
+// Open opens the file at path and decodes it with Decode.
+func Open(path string, respectEXIF bool) (image.Image, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	return Decode(f, respectEXIF)
+}
+
+// Apply returns a copy of img transformed to upright according to the EXIF
+// orientation o. OrientationNormal is returned unchanged.
+//
+// Each case below maps a destination pixel (dx, dy) back to the source
+// pixel it comes from, given the source's width w and height h; 5-8 also
+// swap the output's width and height.
+func Apply(img image.Image, o Orientation) image.Image {
+	switch o {
+	case OrientationFlipHorizontal:
+		return transform(img, false, func(w, h, dx, dy int) (int, int) { return w - 1 - dx, dy })
+	case OrientationRotate180:
+		return transform(img, false, func(w, h, dx, dy int) (int, int) { return w - 1 - dx, h - 1 - dy })
+	case OrientationFlipVertical:
+		return transform(img, false, func(w, h, dx, dy int) (int, int) { return dx, h - 1 - dy })
+	case OrientationTranspose:
+		return transform(img, true, func(w, h, dx, dy int) (int, int) { return dy, dx })
+	case OrientationRotate90:
+		return transform(img, true, func(w, h, dx, dy int) (int, int) { return dy, h - 1 - dx })
+	case OrientationTransverse:
+		return transform(img, true, func(w, h, dx, dy int) (int, int) { return w - 1 - dy, h - 1 - dx })
+	case OrientationRotate270:
+		return transform(img, true, func(w, h, dx, dy int) (int, int) { return w - 1 - dy, dx })
+	default:
+		return img
+	}
+}
+
+// transform builds a new RGBA image of the (possibly dimension-swapped)
+// destination size, filling each pixel by looking up its source pixel
+// through toSource.
+func transform(src image.Image, swapDims bool, toSource func(srcW, srcH, dx, dy int) (int, int)) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	dstW, dstH := w, h
+	if swapDims {
+		dstW, dstH = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for dy := 0; dy < dstH; dy++ {
+		for dx := 0; dx < dstW; dx++ {
+			sx, sy := toSource(w, h, dx, dy)
+			dst.Set(dx, dy, src.At(bounds.Min.X+sx, bounds.Min.Y+sy))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,119 @@
+package imageio
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// labeledImage returns a w x h RGBA image where pixel (x, y) holds
+// colors[y*w+x], so each pixel is individually distinguishable and a
+// transform's effect on position can be read straight off the output.
+func labeledImage(w, h int, colors []color.RGBA) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, colors[y*w+x])
+		}
+	}
+	return img
+}
+
+func checkGrid(t *testing.T, name string, got image.Image, w, h int, want []color.RGBA) {
+	t.Helper()
+
+	b := got.Bounds()
+	if b.Dx() != w || b.Dy() != h {
+		t.Fatalf("%s: got bounds %dx%d, want %dx%d", name, b.Dx(), b.Dy(), w, h)
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bl, a := got.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			wr, wg, wb, wa := want[y*w+x].RGBA()
+			if r != wr || g != wg || bl != wb || a != wa {
+				t.Errorf("%s: pixel (%d,%d) = %v, want %v", name, x, y, got.At(b.Min.X+x, b.Min.Y+y), want[y*w+x])
+			}
+		}
+	}
+}
+
+// TestApplyOrientations exercises all eight EXIF orientation values against
+// a 3x2 source image with a distinct color per pixel:
+//
+//	A B C
+//	D E F
+//
+// The expected grids below were worked out by hand from the EXIF spec's
+// definition of each orientation, independent of Apply's implementation.
+func TestApplyOrientations(t *testing.T) {
+	A := color.RGBA{1, 0, 0, 255}
+	B := color.RGBA{2, 0, 0, 255}
+	C := color.RGBA{3, 0, 0, 255}
+	D := color.RGBA{4, 0, 0, 255}
+	E := color.RGBA{5, 0, 0, 255}
+	F := color.RGBA{6, 0, 0, 255}
+
+	src := labeledImage(3, 2, []color.RGBA{
+		A, B, C,
+		D, E, F,
+	})
+
+	cases := []struct {
+		name string
+		o    Orientation
+		w, h int
+		want []color.RGBA
+	}{
+		{"Normal", OrientationNormal, 3, 2, []color.RGBA{
+			A, B, C,
+			D, E, F,
+		}},
+		{"FlipHorizontal", OrientationFlipHorizontal, 3, 2, []color.RGBA{
+			C, B, A,
+			F, E, D,
+		}},
+		{"Rotate180", OrientationRotate180, 3, 2, []color.RGBA{
+			F, E, D,
+			C, B, A,
+		}},
+		{"FlipVertical", OrientationFlipVertical, 3, 2, []color.RGBA{
+			D, E, F,
+			A, B, C,
+		}},
+		{"Transpose", OrientationTranspose, 2, 3, []color.RGBA{
+			A, D,
+			B, E,
+			C, F,
+		}},
+		{"Rotate90", OrientationRotate90, 2, 3, []color.RGBA{
+			D, A,
+			E, B,
+			F, C,
+		}},
+		{"Transverse", OrientationTransverse, 2, 3, []color.RGBA{
+			F, C,
+			E, B,
+			D, A,
+		}},
+		{"Rotate270", OrientationRotate270, 2, 3, []color.RGBA{
+			C, F,
+			B, E,
+			A, D,
+		}},
+	}
+
+	for _, c := range cases {
+		got := Apply(src, c.o)
+		checkGrid(t, c.name, got, c.w, c.h, c.want)
+	}
+}
+
+func TestApplyNormalReturnsSameImage(t *testing.T) {
+	src := labeledImage(2, 2, []color.RGBA{
+		{1, 0, 0, 255}, {2, 0, 0, 255},
+		{3, 0, 0, 255}, {4, 0, 0, 255},
+	})
+	if got := Apply(src, OrientationNormal); got != image.Image(src) {
+		t.Fatal("OrientationNormal should return the source image unchanged")
+	}
+}
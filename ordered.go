@@ -0,0 +1,138 @@
+package colorquant
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Ordered holds a normalized threshold map used for ordered (a.k.a.
+// threshold-map) dithering. Unlike Dither, which diffuses the quantization
+// error of one pixel onto its neighbours, Ordered compares each pixel
+// against a fixed, tileable threshold and never looks at its neighbours'
+// error. This makes it embarrassingly parallel and gives the characteristic
+// stable, tileable patterns ordered dithering is usually picked for.
+//
+// Matrix entries are expected to already be normalized to [0, 1); the
+// Bayer* and ClusteredDot4x4 presets below satisfy this.
+type Ordered struct {
+	Matrix [][]float32
+}
+
+// Quantize reduces src to at most nq colors from dst's palette, writing the
+// result into dst. For a pixel (x, y) with channel value c in [0, 1], the
+// threshold t = Matrix[y%n][x%n] is used to bias c by (t-0.5)/k, where k is
+// roughly the number of palette levels per channel (so the bias spans about
+// one step between adjacent levels), before rounding to the nearest palette
+// color.
+func (o Ordered) Quantize(src, dst image.Image, nq int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	n := len(o.Matrix)
+
+	paletted, ok := dst.(*image.Paletted)
+	if !ok {
+		paletted = image.NewPaletted(bounds, nil)
+	}
+	pal := paletted.Palette
+	if len(pal) > nq {
+		pal = pal[:nq]
+	}
+
+	// k approximates the number of quantization levels per channel; the
+	// threshold bias is scaled by its reciprocal so that it nudges a pixel
+	// across at most one palette step.
+	k := float32(math.Cbrt(float64(nq)))
+	if k < 2 {
+		k = 2
+	}
+
+	for y := 0; y < height; y++ {
+		row := o.Matrix[y%n]
+		for x := 0; x < width; x++ {
+			t := row[x%n]
+
+			r, g, b, a := src.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			bias := (t - 0.5) / k * 255
+			cr := clamp8(float32(r>>8) + bias)
+			cg := clamp8(float32(g>>8) + bias)
+			cb := clamp8(float32(b>>8) + bias)
+
+			idx := pal.Index(color.RGBA{uint8(cr), uint8(cg), uint8(cb), uint8(a >> 8)})
+			paletted.SetColorIndex(bounds.Min.X+x, bounds.Min.Y+y, uint8(idx))
+		}
+	}
+
+	return paletted
+}
+
+// Bayer2 is the order-2 Bayer threshold matrix, normalized to [0, 1).
+var Bayer2 = Ordered{Matrix: normalize([][]float32{
+	{0, 2},
+	{3, 1},
+}, 4)}
+
+// Bayer4 is the order-4 Bayer threshold matrix, built from Bayer2 via the
+// standard recurrence M_2n = [[4*M_n, 4*M_n+2*J], [4*M_n+3*J, 4*M_n+J]],
+// normalized to [0, 1).
+var Bayer4 = Ordered{Matrix: bayer(2)}
+
+// Bayer8 is the order-8 Bayer threshold matrix, built by applying the same
+// recurrence to Bayer4, normalized to [0, 1).
+var Bayer8 = Ordered{Matrix: bayer(3)}
+
+// ClusteredDot4x4 is a 4x4 clustered-dot threshold matrix, normalized to
+// [0, 1). Unlike the dispersed-dot Bayer matrices, it grows each "dot" from
+// a single center, which mimics classic halftone printing.
+var ClusteredDot4x4 = Ordered{Matrix: normalize([][]float32{
+	{12, 5, 6, 13},
+	{4, 0, 1, 7},
+	{11, 3, 2, 8},
+	{15, 10, 9, 14},
+}, 16)}
+
+// bayer builds the order-2^levels Bayer matrix by repeatedly applying the
+// recurrence M_2n = [[4*M_n, 4*M_n+2*J], [4*M_n+3*J, 4*M_n+J]] / (2n)^2,
+// starting from the order-2 matrix [[0, 2], [3, 1]].
+func bayer(levels int) [][]float32 {
+	m := [][]int{{0, 2}, {3, 1}}
+	for i := 1; i < levels; i++ {
+		n := len(m)
+		next := make([][]int, 2*n)
+		for r := range next {
+			next[r] = make([]int, 2*n)
+		}
+		for r := 0; r < n; r++ {
+			for c := 0; c < n; c++ {
+				next[r][c] = 4 * m[r][c]
+				next[r][c+n] = 4*m[r][c] + 2
+				next[r+n][c] = 4*m[r][c] + 3
+				next[r+n][c+n] = 4*m[r][c] + 1
+			}
+		}
+		m = next
+	}
+
+	size := len(m)
+	out := make([][]float32, size)
+	for r := range m {
+		out[r] = make([]float32, size)
+		for c, v := range m[r] {
+			out[r][c] = float32(v) / float32(size*size)
+		}
+	}
+	return out
+}
+
+// normalize divides every entry of m by divisor, returning a new matrix of
+// float32 thresholds in [0, 1).
+func normalize(m [][]float32, divisor float32) [][]float32 {
+	out := make([][]float32, len(m))
+	for r, row := range m {
+		out[r] = make([]float32, len(row))
+		for c, v := range row {
+			out[r][c] = v / divisor
+		}
+	}
+	return out
+}
@@ -0,0 +1,69 @@
+package colorquant
+
+import "testing"
+
+// checkThresholdMatrix verifies m is square, every entry is in [0, 1), and
+// every entry in [0, n*n) appears exactly once (scaled by n*n), which is
+// the defining property of a dispersed/clustered-dot threshold matrix.
+func checkThresholdMatrix(t *testing.T, name string, m [][]float32) {
+	t.Helper()
+
+	n := len(m)
+	seen := make([]bool, n*n)
+	for _, row := range m {
+		if len(row) != n {
+			t.Fatalf("%s: row length %d, want square matrix of size %d", name, len(row), n)
+		}
+		for _, v := range row {
+			if v < 0 || v >= 1 {
+				t.Fatalf("%s: threshold %v out of [0, 1) range", name, v)
+			}
+			rank := int(v*float32(n*n) + 0.5)
+			if rank < 0 || rank >= n*n || seen[rank] {
+				t.Fatalf("%s: threshold %v does not map to a unique rank in [0, %d)", name, v, n*n)
+			}
+			seen[rank] = true
+		}
+	}
+	for rank, ok := range seen {
+		if !ok {
+			t.Fatalf("%s: rank %d never appears in the matrix", name, rank)
+		}
+	}
+}
+
+func TestThresholdMatrices(t *testing.T) {
+	cases := []struct {
+		name string
+		m    [][]float32
+	}{
+		{"Bayer2", Bayer2.Matrix},
+		{"Bayer4", Bayer4.Matrix},
+		{"Bayer8", Bayer8.Matrix},
+		{"ClusteredDot4x4", ClusteredDot4x4.Matrix},
+	}
+	for _, c := range cases {
+		checkThresholdMatrix(t, c.name, c.m)
+	}
+}
+
+func TestBayerRecurrenceMatchesPresets(t *testing.T) {
+	if got, want := len(bayer(2)), len(Bayer4.Matrix); got != want {
+		t.Fatalf("bayer(2) size = %d, want %d", got, want)
+	}
+	for y, row := range bayer(2) {
+		for x, v := range row {
+			if v != Bayer4.Matrix[y][x] {
+				t.Fatalf("bayer(2)[%d][%d] = %v, want %v (Bayer4)", y, x, v, Bayer4.Matrix[y][x])
+			}
+		}
+	}
+
+	for y, row := range bayer(3) {
+		for x, v := range row {
+			if v != Bayer8.Matrix[y][x] {
+				t.Fatalf("bayer(3)[%d][%d] = %v, want %v (Bayer8)", y, x, v, Bayer8.Matrix[y][x])
+			}
+		}
+	}
+}
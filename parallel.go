@@ -0,0 +1,176 @@
+package colorquant
+
+import (
+	"image"
+	"image/color"
+	"sync"
+)
+
+// quantizeParallel implements error diffusion using a wavefront schedule:
+// rows are handed out to d.Parallelism workers, but instead of requiring a
+// row's whole band above it to finish, a row only waits for the specific
+// columns of the rows above it that its matrix entries actually read from.
+// Since every matrix row only reaches a few columns either side of center,
+// that wait is satisfied long before the row above finishes, so multiple
+// rows make progress at once, staggered diagonally across the image like a
+// skewed pipeline - unlike banding the image and waiting for a whole band
+// to complete, which serializes band after band and parallelizes nothing.
+//
+// To keep the output bit-identical to the serial scan despite that
+// concurrency, a row doesn't diffuse its error forward into shared
+// accumulators, which could be written to by multiple rows in an order
+// that differs from the serial scan's and changes float32 rounding.
+// Instead each row records its own quantization error, and every pixel
+// pulls the contributions it needs from already-recorded rows, summing
+// them in the same row/column order the serial scan would have added
+// them in.
+//
+// Serpentine scanning is not supported here: reversing direction on
+// alternate rows would also reverse which pixels feed which, so Quantize
+// falls back to the serial path when serpentine is requested.
+func (d Dither) quantizeParallel(src image.Image, paletted *image.Paletted, pal color.Palette, width, height int) {
+	workers := d.Parallelism
+	if workers > height {
+		workers = height
+	}
+
+	depth := len(d.Matrix)
+
+	// minOffset[r] is the smallest (most negative) column offset matrix row
+	// r writes to. A negative offset means the source column is further
+	// right than the destination, so a pixel at column x can only use
+	// matrix row r's contribution once the row r rows above it has
+	// recorded column x-minOffset[r].
+	minOffset := make([]int, depth)
+	for r, weights := range d.Matrix {
+		center := (len(weights) - 1) / 2
+		var m int
+		for col, w := range weights {
+			if w == 0 {
+				continue
+			}
+			if off := col - center; off < m {
+				m = off
+			}
+		}
+		minOffset[r] = m
+	}
+
+	diffR := make([][]float32, height)
+	diffG := make([][]float32, height)
+	diffB := make([][]float32, height)
+	for y := range diffR {
+		diffR[y] = make([]float32, width)
+		diffG[y] = make([]float32, width)
+		diffB[y] = make([]float32, width)
+	}
+
+	var mu sync.Mutex
+	cond := sync.NewCond(&mu)
+	// progress[y] is how many leading columns of row y have been recorded.
+	progress := make([]int, height)
+	nextRow := 0
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				if nextRow >= height {
+					mu.Unlock()
+					return
+				}
+				y := nextRow
+				nextRow++
+				mu.Unlock()
+
+				d.diffuseRowWave(src, paletted, pal, y, width, depth, minOffset, diffR, diffG, diffB, progress, &mu, cond)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// diffuseRowWave quantizes row y, pulling the error diffused into it from
+// already-recorded rows above (waiting only on the specific columns it
+// needs) and from its own earlier columns, then records its own
+// quantization error for later rows to pull from.
+func (d Dither) diffuseRowWave(src image.Image, paletted *image.Paletted, pal color.Palette, y, width, depth int, minOffset []int, diffR, diffG, diffB [][]float32, progress []int, mu *sync.Mutex, cond *sync.Cond) {
+	bx, by := src.Bounds().Min.X, src.Bounds().Min.Y
+
+	for x := 0; x < width; x++ {
+		if y > 0 {
+			mu.Lock()
+			for r := 1; r < depth && y-r >= 0; r++ {
+				// Cap at width: once the row above has recorded every
+				// column it has, it can never write any later column we
+				// might still be waiting on, so there's nothing left to
+				// wait for.
+				need := x - minOffset[r] + 1
+				if need > width {
+					need = width
+				}
+				for progress[y-r] < need {
+					cond.Wait()
+				}
+			}
+			mu.Unlock()
+		}
+
+		// Sum the contributions diffused into (y, x) in the same order the
+		// serial scan would have accumulated them: oldest source row
+		// first, and within a row in the order its columns are visited
+		// left to right (i.e. descending col, since col tracks ascending
+		// offset).
+		var accR, accG, accB float32
+		for r := depth - 1; r >= 0; r-- {
+			sy := y - r
+			if sy < 0 {
+				continue
+			}
+			weights := d.Matrix[r]
+			center := (len(weights) - 1) / 2
+			for col := len(weights) - 1; col >= 0; col-- {
+				if r == 0 && col == center {
+					continue
+				}
+				w := weights[col]
+				if w == 0 {
+					continue
+				}
+				sx := x - (col - center)
+				if sx < 0 || sx >= width {
+					continue
+				}
+				accR += diffR[sy][sx] * w
+				accG += diffG[sy][sx] * w
+				accB += diffB[sy][sx] * w
+			}
+		}
+
+		cr, cg, cb, ca := src.At(bx+x, by+y).RGBA()
+		wr, wg, wb := d.ToWorkingSpace(float32(cr>>8), float32(cg>>8), float32(cb>>8))
+		wr += accR
+		wg += accG
+		wb += accB
+
+		fr, fg, fb := d.FromWorkingSpace(wr, wg, wb)
+		fr, fg, fb = clamp8(fr), clamp8(fg), clamp8(fb)
+
+		idx := pal.Index(color.RGBA{uint8(fr), uint8(fg), uint8(fb), uint8(ca >> 8)})
+		paletted.SetColorIndex(bx+x, by+y, uint8(idx))
+
+		qr, qg, qb, _ := pal[idx].RGBA()
+		qwr, qwg, qwb := d.ToWorkingSpace(float32(qr>>8), float32(qg>>8), float32(qb>>8))
+		diffR[y][x] = wr - qwr
+		diffG[y][x] = wg - qwg
+		diffB[y][x] = wb - qwb
+
+		mu.Lock()
+		progress[y] = x + 1
+		cond.Broadcast()
+		mu.Unlock()
+	}
+}
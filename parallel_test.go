@@ -0,0 +1,98 @@
+package colorquant
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"testing"
+)
+
+// gradientImage returns a w x h image with enough channel variation to
+// exercise error diffusion across rows handled by different workers.
+func gradientImage(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{
+				R: uint8((x * 255) / w),
+				G: uint8((y * 255) / h),
+				B: uint8(((x + y) * 255) / (w + h)),
+				A: 255,
+			})
+		}
+	}
+	return img
+}
+
+func TestQuantizeParallelMatchesSerial(t *testing.T) {
+	matrices := map[string][][]float32{
+		"FloydSteinberg": {
+			{0.0, 0.0, 0.0, 7.0 / 48.0, 5.0 / 48.0},
+			{3.0 / 48.0, 5.0 / 48.0, 7.0 / 48.0, 5.0 / 48.0, 3.0 / 48.0},
+			{1.0 / 48.0, 3.0 / 48.0, 5.0 / 48.0, 3.0 / 48.0, 1.0 / 48.0},
+		},
+		"Stucki": {
+			{0.0, 0.0, 0.0, 8.0 / 42.0, 4.0 / 42.0},
+			{2.0 / 42.0, 4.0 / 42.0, 8.0 / 42.0, 4.0 / 42.0, 2.0 / 42.0},
+			{1.0 / 42.0, 2.0 / 42.0, 4.0 / 42.0, 2.0 / 42.0, 1.0 / 42.0},
+		},
+	}
+
+	src := gradientImage(50, 50)
+
+	for name, matrix := range matrices {
+		t.Run(name, func(t *testing.T) {
+			serial := Dither{Matrix: matrix}
+			dst := image.NewPaletted(src.Bounds(), palette.WebSafe)
+			want := serial.Quantize(src, dst, len(palette.WebSafe), true, false).(*image.Paletted)
+
+			for _, workers := range []int{2, 4} {
+				parallel := Dither{Matrix: matrix, Parallelism: workers}
+				dst := image.NewPaletted(src.Bounds(), palette.WebSafe)
+				got := parallel.Quantize(src, dst, len(palette.WebSafe), true, false).(*image.Paletted)
+
+				if len(got.Pix) != len(want.Pix) {
+					t.Fatalf("parallelism=%d: pixel buffer length mismatch: got %d, want %d", workers, len(got.Pix), len(want.Pix))
+				}
+				for i := range want.Pix {
+					if got.Pix[i] != want.Pix[i] {
+						t.Fatalf("parallelism=%d: pixel index byte %d differs: got %d, want %d", workers, i, got.Pix[i], want.Pix[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkQuantizeSerial(b *testing.B) {
+	src := gradientImage(512, 512)
+	d := Dither{Matrix: [][]float32{
+		{0.0, 0.0, 0.0, 7.0 / 48.0, 5.0 / 48.0},
+		{3.0 / 48.0, 5.0 / 48.0, 7.0 / 48.0, 5.0 / 48.0, 3.0 / 48.0},
+		{1.0 / 48.0, 3.0 / 48.0, 5.0 / 48.0, 3.0 / 48.0, 1.0 / 48.0},
+	}}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dst := image.NewPaletted(src.Bounds(), palette.WebSafe)
+		d.Quantize(src, dst, len(palette.WebSafe), true, true)
+	}
+}
+
+func BenchmarkQuantizeParallel(b *testing.B) {
+	src := gradientImage(512, 512)
+	d := Dither{
+		Matrix: [][]float32{
+			{0.0, 0.0, 0.0, 7.0 / 48.0, 5.0 / 48.0},
+			{3.0 / 48.0, 5.0 / 48.0, 7.0 / 48.0, 5.0 / 48.0, 3.0 / 48.0},
+			{1.0 / 48.0, 3.0 / 48.0, 5.0 / 48.0, 3.0 / 48.0, 1.0 / 48.0},
+		},
+		Parallelism: 4,
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dst := image.NewPaletted(src.Bounds(), palette.WebSafe)
+		d.Quantize(src, dst, len(palette.WebSafe), true, false)
+	}
+}
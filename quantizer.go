@@ -0,0 +1,279 @@
+package colorquant
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"sort"
+)
+
+// Quantizer builds an n-color palette that best represents img. It is the
+// counterpart to Dither and Ordered, which only map pixels onto a palette
+// that already exists.
+type Quantizer interface {
+	// Quantize returns a palette of at most n colors approximating img.
+	Quantize(img image.Image, n int) color.Palette
+}
+
+// FixedQuantizer always returns the first n colors of Palette, ignoring img
+// entirely. It lets a pre-built palette, such as image/color/palette.WebSafe,
+// be used wherever a Quantizer is expected.
+type FixedQuantizer struct {
+	Palette color.Palette
+}
+
+// Quantize implements Quantizer.
+func (f FixedQuantizer) Quantize(_ image.Image, n int) color.Palette {
+	pal := f.Palette
+	if n > 0 && len(pal) > n {
+		pal = pal[:n]
+	}
+	return pal
+}
+
+// colorBox is an axis-aligned box in RGB space holding the pixels it covers,
+// used by MedianCutQuantizer to recursively split the color cube.
+type colorBox struct {
+	pixels []color.RGBA
+}
+
+// rangeAxis returns the channel (0 = R, 1 = G, 2 = B) with the greatest
+// value range across the box's pixels, along with that range.
+func (b colorBox) rangeAxis() (axis int, span uint8) {
+	var min, max [3]uint8
+	min = [3]uint8{255, 255, 255}
+	for _, p := range b.pixels {
+		c := [3]uint8{p.R, p.G, p.B}
+		for i := 0; i < 3; i++ {
+			if c[i] < min[i] {
+				min[i] = c[i]
+			}
+			if c[i] > max[i] {
+				max[i] = c[i]
+			}
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if s := max[i] - min[i]; s >= span {
+			axis, span = i, s
+		}
+	}
+	return axis, span
+}
+
+// mean returns the average color of the pixels in the box.
+func (b colorBox) mean() color.RGBA {
+	var r, g, bl, a int
+	for _, p := range b.pixels {
+		r += int(p.R)
+		g += int(p.G)
+		bl += int(p.B)
+		a += int(p.A)
+	}
+	n := len(b.pixels)
+	return color.RGBA{uint8(r / n), uint8(g / n), uint8(bl / n), uint8(a / n)}
+}
+
+// split partitions the box in two around the median of its widest axis.
+func (b colorBox) split() (colorBox, colorBox) {
+	axis, _ := b.rangeAxis()
+	pixels := make([]color.RGBA, len(b.pixels))
+	copy(pixels, b.pixels)
+
+	sort.Slice(pixels, func(i, j int) bool {
+		switch axis {
+		case 0:
+			return pixels[i].R < pixels[j].R
+		case 1:
+			return pixels[i].G < pixels[j].G
+		default:
+			return pixels[i].B < pixels[j].B
+		}
+	})
+
+	mid := len(pixels) / 2
+	return colorBox{pixels[:mid]}, colorBox{pixels[mid:]}
+}
+
+// MedianCutQuantizer builds a palette using the median-cut algorithm: the
+// color cube is recursively split along the axis of greatest range until n
+// leaf boxes remain, and each leaf's mean color becomes a palette entry.
+type MedianCutQuantizer struct{}
+
+// Quantize implements Quantizer.
+func (MedianCutQuantizer) Quantize(img image.Image, n int) color.Palette {
+	pixels := collectPixels(img)
+	if len(pixels) == 0 || n <= 0 {
+		return color.Palette{}
+	}
+
+	boxes := []colorBox{{pixels}}
+	for len(boxes) < n {
+		// Split the box with the greatest color range, so palette slots go
+		// to color-diverse regions instead of large flat ones that merely
+		// hold the most pixels.
+		widest := -1
+		var widestSpan uint8
+		for i, b := range boxes {
+			if len(b.pixels) < 2 {
+				continue
+			}
+			if _, span := b.rangeAxis(); widest == -1 || span > widestSpan {
+				widest, widestSpan = i, span
+			}
+		}
+		if widest == -1 {
+			break
+		}
+
+		a, c := boxes[widest].split()
+		boxes[widest] = a
+		boxes = append(boxes, c)
+	}
+
+	pal := make(color.Palette, len(boxes))
+	for i, b := range boxes {
+		pal[i] = b.mean()
+	}
+	return pal
+}
+
+// KMeansQuantizer builds a palette by clustering an image's pixels into n
+// groups in RGB space, seeding the initial centers with k-means++ and then
+// iterating assign/update until the centers stop moving or MaxIterations is
+// reached.
+type KMeansQuantizer struct {
+	// MaxIterations caps the number of assign/update passes. Zero means use
+	// a sensible default.
+	MaxIterations int
+}
+
+// Quantize implements Quantizer.
+func (q KMeansQuantizer) Quantize(img image.Image, n int) color.Palette {
+	pixels := collectPixels(img)
+	if len(pixels) == 0 || n <= 0 {
+		return color.Palette{}
+	}
+	if n > len(pixels) {
+		n = len(pixels)
+	}
+
+	maxIter := q.MaxIterations
+	if maxIter <= 0 {
+		maxIter = 20
+	}
+
+	centers := kmeansPlusPlusSeed(pixels, n)
+	assignments := make([]int, len(pixels))
+
+	for iter := 0; iter < maxIter; iter++ {
+		changed := false
+		for i, p := range pixels {
+			best, bestDist := 0, sqDist(p, centers[0])
+			for c := 1; c < len(centers); c++ {
+				if d := sqDist(p, centers[c]); d < bestDist {
+					best, bestDist = c, d
+				}
+			}
+			if assignments[i] != best {
+				assignments[i] = best
+				changed = true
+			}
+		}
+
+		var sumR, sumG, sumB, count = make([]int, n), make([]int, n), make([]int, n), make([]int, n)
+		for i, p := range pixels {
+			c := assignments[i]
+			sumR[c] += int(p.R)
+			sumG[c] += int(p.G)
+			sumB[c] += int(p.B)
+			count[c]++
+		}
+		for c := 0; c < n; c++ {
+			if count[c] == 0 {
+				continue
+			}
+			centers[c] = color.RGBA{
+				uint8(sumR[c] / count[c]),
+				uint8(sumG[c] / count[c]),
+				uint8(sumB[c] / count[c]),
+				255,
+			}
+		}
+
+		if !changed {
+			break
+		}
+	}
+
+	pal := make(color.Palette, len(centers))
+	for i, c := range centers {
+		pal[i] = c
+	}
+	return pal
+}
+
+// kmeansPlusPlusSeed picks n initial centers from pixels using k-means++:
+// the first center is chosen uniformly at random and each subsequent center
+// is chosen with probability proportional to its squared distance from the
+// nearest center already picked.
+func kmeansPlusPlusSeed(pixels []color.RGBA, n int) []color.RGBA {
+	centers := make([]color.RGBA, 0, n)
+	centers = append(centers, pixels[rand.Intn(len(pixels))])
+
+	for len(centers) < n {
+		var total int64
+		dists := make([]int64, len(pixels))
+		for i, p := range pixels {
+			best := int64(sqDist(p, centers[0]))
+			for _, c := range centers[1:] {
+				if d := int64(sqDist(p, c)); d < best {
+					best = d
+				}
+			}
+			dists[i] = best
+			total += best
+		}
+
+		if total == 0 {
+			centers = append(centers, pixels[rand.Intn(len(pixels))])
+			continue
+		}
+
+		// Pick the next center with probability proportional to its
+		// squared distance from the nearest center already chosen.
+		target := rand.Int63n(total)
+		var cum int64
+		for i, d := range dists {
+			cum += d
+			if cum > target {
+				centers = append(centers, pixels[i])
+				break
+			}
+		}
+	}
+
+	return centers
+}
+
+// sqDist returns the squared Euclidean distance between two colors in RGB
+// space.
+func sqDist(a, b color.RGBA) int {
+	dr := int(a.R) - int(b.R)
+	dg := int(a.G) - int(b.G)
+	db := int(a.B) - int(b.B)
+	return dr*dr + dg*dg + db*db
+}
+
+// collectPixels flattens img into a slice of its RGBA pixels.
+func collectPixels(img image.Image) []color.RGBA {
+	bounds := img.Bounds()
+	pixels := make([]color.RGBA, 0, bounds.Dx()*bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			pixels = append(pixels, color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)})
+		}
+	}
+	return pixels
+}
@@ -0,0 +1,83 @@
+package colorquant
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// multiColorImage returns a w x h image with n distinct solid-color bands,
+// stacked vertically, so a quantizer has real color variety to work with.
+func multiColorImage(w, h, n int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	colors := []color.RGBA{
+		{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255},
+		{255, 255, 0, 255}, {0, 255, 255, 255}, {255, 0, 255, 255},
+	}
+	for y := 0; y < h; y++ {
+		band := y * n / h
+		c := colors[band%len(colors)]
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestMedianCutQuantizerPaletteSize(t *testing.T) {
+	img := multiColorImage(40, 40, 4)
+	for _, n := range []int{1, 2, 4, 8} {
+		pal := MedianCutQuantizer{}.Quantize(img, n)
+		if len(pal) > n {
+			t.Errorf("n=%d: got palette of size %d, want at most %d", n, len(pal), n)
+		}
+		if len(pal) == 0 {
+			t.Errorf("n=%d: got empty palette for a non-degenerate image", n)
+		}
+	}
+}
+
+func TestMedianCutQuantizerDegenerateInput(t *testing.T) {
+	solid := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			solid.Set(x, y, color.RGBA{100, 100, 100, 255})
+		}
+	}
+	if pal := (MedianCutQuantizer{}).Quantize(solid, 8); len(pal) == 0 {
+		t.Fatal("solid-color image produced an empty palette")
+	}
+
+	empty := image.NewRGBA(image.Rect(0, 0, 0, 0))
+	if pal := (MedianCutQuantizer{}).Quantize(empty, 8); len(pal) != 0 {
+		t.Fatalf("empty image should yield an empty palette, got %d entries", len(pal))
+	}
+
+	if pal := (MedianCutQuantizer{}).Quantize(multiColorImage(10, 10, 2), 0); len(pal) != 0 {
+		t.Fatalf("n=0 should yield an empty palette, got %d entries", len(pal))
+	}
+}
+
+func TestKMeansQuantizerPaletteSize(t *testing.T) {
+	img := multiColorImage(40, 40, 4)
+	for _, n := range []int{1, 2, 4, 8} {
+		pal := KMeansQuantizer{}.Quantize(img, n)
+		if len(pal) > n {
+			t.Errorf("n=%d: got palette of size %d, want at most %d", n, len(pal), n)
+		}
+		if len(pal) == 0 {
+			t.Errorf("n=%d: got empty palette for a non-degenerate image", n)
+		}
+	}
+}
+
+func TestKMeansQuantizerDegenerateInput(t *testing.T) {
+	empty := image.NewRGBA(image.Rect(0, 0, 0, 0))
+	if pal := (KMeansQuantizer{}).Quantize(empty, 8); len(pal) != 0 {
+		t.Fatalf("empty image should yield an empty palette, got %d entries", len(pal))
+	}
+
+	if pal := (KMeansQuantizer{}).Quantize(multiColorImage(10, 10, 2), 0); len(pal) != 0 {
+		t.Fatalf("n=0 should yield an empty palette, got %d entries", len(pal))
+	}
+}